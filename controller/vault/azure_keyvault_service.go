@@ -3,15 +3,29 @@ package vault
 import (
 	"bytes"
 	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
 	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
 	"encoding/pem"
 	"fmt"
+	"math/big"
 
-	"golang.org/x/crypto/pkcs12"
+	"github.com/joho/godotenv"
+	"software.sslmate.com/src/go-pkcs12"
 	corev1 "k8s.io/api/core/v1"
-
-	"github.com/Azure/azure-sdk-for-go/services/keyvault/2016-10-01/keyvault"
-	"github.com/Azure/go-autorest/autorest/azure/auth"
+	"sigs.k8s.io/yaml"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/Azure/azure-sdk-for-go/sdk/azidentity"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azcertificates"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azsecrets"
+	"github.com/Azure/go-autorest/autorest/azure"
 	azureKeyVaultSecretv1alpha1 "github.com/SparebankenVest/azure-keyvault-controller/pkg/apis/azurekeyvaultcontroller/v1alpha1"
 )
 
@@ -38,57 +52,156 @@ const (
 type AzureKeyVaultService struct {
 }
 
-// NewAzureKeyVaultService creates a new AzureKeyVaultService using built in Managed Service Identity for authentication
+// NewAzureKeyVaultService creates a new AzureKeyVaultService. Authentication is
+// resolved by azidentity.NewDefaultAzureCredential, which tries Workload
+// Identity (projected service account token federation) first and falls back
+// through the rest of the default credential chain, so the same binary works
+// both on AKS with Workload Identity and for local development.
 func NewAzureKeyVaultService() *AzureKeyVaultService {
 	return &AzureKeyVaultService{}
 }
 
-// GetSecret returns a secret from Azure Key Vault
+// SecretStore is implemented by anything that can resolve an
+// AzureKeyVaultSecret's secret, certificate or key material. It lets the
+// controller select a backend per-CR (for example via spec.vault.cloud) and
+// is the seam a non-Azure backend - AWS Secrets Manager, GCP Secret Manager -
+// would implement in the future.
+type SecretStore interface {
+	GetSecret(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error)
+	GetCertificate(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error)
+	GetKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error)
+}
+
+var _ SecretStore = &AzureKeyVaultService{}
+
+// GetSecret dispatches to GetCertificate or GetKey when secret is typed as
+// such, and otherwise fetches the raw secret value from Azure Key Vault.
+// This dispatch is what lets callers use a single entry point regardless of
+// secret.Spec.Vault.Object.Type, mirroring how the Secrets, Certificates and
+// Keys APIs overlap in Key Vault itself (a Certificate's material is also
+// readable through the plain Secrets API, just not split into tls.crt/tls.key).
 func (a *AzureKeyVaultService) GetSecret(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
 	switch secret.Spec.Vault.Object.Type {
 	case AzureKeyVaultObjectTypeCertificate:
-		return getCertificate(secret)
+		return a.GetCertificate(secret)
+	case AzureKeyVaultObjectTypeKey:
+		return a.GetKey(secret)
 	default:
-		return getSecret(secret)
+		return a.getSecretValue(secret)
 	}
 }
 
-func getSecret(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+// getSecretValue fetches the raw secret value from Azure Key Vault.
+func (a *AzureKeyVaultService) getSecretValue(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
 	secretValue := make(map[string][]byte, 1)
 
 	//Get secret value from Azure Key Vault
-	vaultClient, err := getClient("https://vault.azure.net")
+	secretsClient, err := getSecretsClient(secret)
 	if err != nil {
 		return secretValue, err
 	}
 
-	baseURL := fmt.Sprintf("https://%s.vault.azure.net", secret.Spec.Vault.Name)
-	secretBundle, err := vaultClient.GetSecret(context.Background(), baseURL, secret.Spec.Vault.Object.Name, "")
-
+	resp, err := secretsClient.GetSecret(context.Background(), secret.Spec.Vault.Object.Name, "", nil)
 	if err != nil {
 		return secretValue, err
 	}
 
+	return splitSecretValue(*resp.Value, secret)
+}
+
+// AzureKeyVaultSecretFormat defines how to interpret a Key Vault secret value
+// before mapping it to output secret keys.
+type AzureKeyVaultSecretFormat string
+
+const (
+	// AzureKeyVaultSecretFormatRaw copies the whole secret value verbatim into every output key - the default.
+	AzureKeyVaultSecretFormatRaw AzureKeyVaultSecretFormat = "raw"
+	// AzureKeyVaultSecretFormatJSON parses the secret value as a JSON object and maps its fields to output keys.
+	AzureKeyVaultSecretFormatJSON AzureKeyVaultSecretFormat = "json"
+	// AzureKeyVaultSecretFormatYAML parses the secret value as a YAML object and maps its fields to output keys.
+	AzureKeyVaultSecretFormatYAML AzureKeyVaultSecretFormat = "yaml"
+	// AzureKeyVaultSecretFormatDotenv parses the secret value as dotenv (KEY=value lines) and maps its fields to output keys.
+	AzureKeyVaultSecretFormatDotenv AzureKeyVaultSecretFormat = "dotenv"
+)
+
+// splitSecretValue maps value onto secret.Spec.OutputSecret.Keys. With the
+// default "raw" sourceFormat, value is copied verbatim into every DstName,
+// same as before structured formats existed. Otherwise value is parsed
+// according to sourceFormat and each key's SrcName is looked up in the
+// resulting fields and written to DstName, so a single Key Vault secret
+// holding e.g. a JSON blob can be fanned out into several Kubernetes secret
+// keys instead of requiring one vault secret per variable.
+func splitSecretValue(value string, secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+	secretValue := make(map[string][]byte, len(secret.Spec.OutputSecret.Keys))
+
+	format := AzureKeyVaultSecretFormat(secret.Spec.Vault.Object.SourceFormat)
+	if format == "" || format == AzureKeyVaultSecretFormatRaw {
+		for _, key := range secret.Spec.OutputSecret.Keys {
+			secretValue[key.DstName] = []byte(value)
+		}
+		return secretValue, nil
+	}
+
+	fields, err := parseSourceFields(value, format)
+	if err != nil {
+		return nil, err
+	}
+
 	for _, key := range secret.Spec.OutputSecret.Keys {
-		secretValue[key.DstName] = []byte(*secretBundle.Value)
+		fieldValue, ok := fields[key.SrcName]
+		if !ok {
+			return nil, fmt.Errorf("source field '%s' not found in azure key vault secret '%s'", key.SrcName, secret.Spec.Vault.Object.Name)
+		}
+		secretValue[key.DstName] = []byte(fieldValue)
 	}
 
 	return secretValue, nil
 }
 
-// getCertificate return public/private certificate pems
-func getCertificate(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+// parseSourceFields parses value according to format into a flat field name -> value map.
+func parseSourceFields(value string, format AzureKeyVaultSecretFormat) (map[string]string, error) {
+	switch format {
+	case AzureKeyVaultSecretFormatJSON:
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(value), &fields); err != nil {
+			return nil, fmt.Errorf("failed to parse azure key vault secret as json, error: %+v", err)
+		}
+		return stringifyFields(fields), nil
+	case AzureKeyVaultSecretFormatYAML:
+		var fields map[string]interface{}
+		if err := yaml.Unmarshal([]byte(value), &fields); err != nil {
+			return nil, fmt.Errorf("failed to parse azure key vault secret as yaml, error: %+v", err)
+		}
+		return stringifyFields(fields), nil
+	case AzureKeyVaultSecretFormatDotenv:
+		fields, err := godotenv.Unmarshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse azure key vault secret as dotenv, error: %+v", err)
+		}
+		return fields, nil
+	default:
+		return nil, fmt.Errorf("unsupported azure key vault secret sourceFormat '%s'", format)
+	}
+}
+
+func stringifyFields(fields map[string]interface{}) map[string]string {
+	stringified := make(map[string]string, len(fields))
+	for name, value := range fields {
+		stringified[name] = fmt.Sprintf("%v", value)
+	}
+	return stringified
+}
+
+// GetCertificate return public/private certificate pems
+func (a *AzureKeyVaultService) GetCertificate(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
 	secretValue := make(map[string][]byte, 2)
 
-	//Get secret value from Azure Key Vault
-	vaultClient, err := getClient("https://vault.azure.net")
+	certificatesClient, err := getCertificatesClient(secret)
 	if err != nil {
 		return secretValue, err
 	}
 
-	baseURL := fmt.Sprintf("https://%s.vault.azure.net", secret.Spec.Vault.Name)
-
-	certBundle, err := vaultClient.GetCertificate(context.Background(), baseURL, secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version)
+	certBundle, err := certificatesClient.GetCertificate(context.Background(), secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version, nil)
 	if err != nil {
 		return secretValue, fmt.Errorf("failed to get certificate from azure key vault, error: %+v", err)
 	}
@@ -97,42 +210,371 @@ func getCertificate(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (ma
 		return nil, fmt.Errorf("unable to get certificate since it's not exportable")
 	}
 
-	secretBundle, err := vaultClient.GetSecret(context.Background(), baseURL, secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version)
+	secretsClient, err := getSecretsClient(secret)
+	if err != nil {
+		return secretValue, err
+	}
+
+	secretBundle, err := secretsClient.GetSecret(context.Background(), secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version, nil)
 	if err != nil {
 		return secretValue, fmt.Errorf("failed to get secret from azure key vault, error: %+v", err)
 	}
 
 	switch *secretBundle.ContentType {
 	case AzureKeyVaultCertificateTypePem:
-		return extractPemCertificate(*secretBundle.Value), nil
+		return extractPemCertificate(*secretBundle.Value, secret), nil
 	case AzureKeyVaultCertificateTypePfx:
-		return extractPfxCertificate(*secretBundle.Value)
+		return extractPfxCertificate(*secretBundle.Value, secret)
 	default:
 		return secretValue, fmt.Errorf("azure key vault secret with content-type '%s' not supported", *secretBundle.ContentType)
 	}
 }
 
-func extractPemCertificate(pemCert string) map[string][]byte {
-	// TODO: Support cert chains
+const (
+	// AzureKeyVaultKeyTypePublicKeyPem is the output key holding the PEM encoded public key
+	AzureKeyVaultKeyTypePublicKeyPem = "publicKey.pem"
+	// AzureKeyVaultKeyTypePublicKeyJWK is the output key holding the raw JWK public key
+	AzureKeyVaultKeyTypePublicKeyJWK = "publicKey.jwk"
+)
+
+// GetKey returns the public part of a Key Vault Key in both PEM and JWK form.
+// The private/non-exportable material never leaves the vault - callers that
+// need to use the key for signing or encryption should use SignWithKey,
+// VerifyWithKey, EncryptWithKey, DecryptWithKey, WrapKeyWithKey or
+// UnwrapKeyWithKey instead.
+func (a *AzureKeyVaultService) GetKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
 	secretValue := make(map[string][]byte, 2)
-	privateDer, rest := pem.Decode([]byte(pemCert))
-	publicDer, _ := pem.Decode(rest)
 
-	secretValue[corev1.TLSCertKey] = pem.EncodeToMemory(publicDer)
-	secretValue[corev1.TLSPrivateKeyKey] = pem.EncodeToMemory(privateDer)
+	keysClient, err := getKeysClient(secret)
+	if err != nil {
+		return secretValue, err
+	}
+
+	keyBundle, err := keysClient.GetKey(context.Background(), secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version, nil)
+	if err != nil {
+		return secretValue, fmt.Errorf("failed to get key from azure key vault, error: %+v", err)
+	}
+
+	jwkJSON, err := keyBundle.Key.MarshalJSON()
+	if err != nil {
+		return secretValue, fmt.Errorf("failed to marshal jwk for key '%s', error: %+v", secret.Spec.Vault.Object.Name, err)
+	}
+
+	publicKey, err := publicKeyFromJWK(keyBundle.Key)
+	if err != nil {
+		return secretValue, fmt.Errorf("failed to derive public key for key '%s', error: %+v", secret.Spec.Vault.Object.Name, err)
+	}
+
+	publicKeyDer, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return secretValue, fmt.Errorf("failed to marshal public key for key '%s', error: %+v", secret.Spec.Vault.Object.Name, err)
+	}
+
+	secretValue[AzureKeyVaultKeyTypePublicKeyPem] = pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicKeyDer})
+	secretValue[AzureKeyVaultKeyTypePublicKeyJWK] = jwkJSON
+
+	return secretValue, nil
+}
+
+// publicKeyFromJWK converts a Key Vault JSONWebKey (RSA or EC) into a Go
+// crypto.PublicKey, since the key material never leaves the HSM.
+func publicKeyFromJWK(jwk *azkeys.JSONWebKey) (crypto.PublicKey, error) {
+	switch *jwk.Kty {
+	case azkeys.KeyTypeRSA, azkeys.KeyTypeRSAHSM:
+		if jwk.N == nil || jwk.E == nil {
+			return nil, fmt.Errorf("rsa jwk is missing modulus or exponent")
+		}
+		if len(jwk.E) > 8 {
+			return nil, fmt.Errorf("rsa jwk exponent is %d bytes, want at most 8", len(jwk.E))
+		}
+		eBytes := make([]byte, 8)
+		copy(eBytes[8-len(jwk.E):], jwk.E)
+		return &rsa.PublicKey{
+			N: new(big.Int).SetBytes(jwk.N),
+			E: int(binary.BigEndian.Uint64(eBytes)),
+		}, nil
+	case azkeys.KeyTypeEC, azkeys.KeyTypeECHSM:
+		if jwk.X == nil || jwk.Y == nil {
+			return nil, fmt.Errorf("ec jwk is missing x or y coordinate")
+		}
+		var curve elliptic.Curve
+		switch *jwk.Crv {
+		case azkeys.CurveNameP256:
+			curve = elliptic.P256()
+		case azkeys.CurveNameP384:
+			curve = elliptic.P384()
+		case azkeys.CurveNameP521:
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported ec curve '%s'", *jwk.Crv)
+		}
+		return &ecdsa.PublicKey{
+			Curve: curve,
+			X:     new(big.Int).SetBytes(jwk.X),
+			Y:     new(big.Int).SetBytes(jwk.Y),
+		}, nil
+	default:
+		return nil, fmt.Errorf("unsupported json web key type '%s'", *jwk.Kty)
+	}
+}
+
+// SignWithKey signs digest with the named Key Vault key using algorithm,
+// without the private key material ever leaving the vault.
+func (a *AzureKeyVaultService) SignWithKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret, algorithm azkeys.SignatureAlgorithm, digest []byte) ([]byte, error) {
+	keysClient, err := getKeysClient(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := keysClient.Sign(context.Background(), secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version, azkeys.SignParameters{
+		Algorithm: &algorithm,
+		Value:     digest,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign digest with azure key vault key, error: %+v", err)
+	}
+
+	return result.Result, nil
+}
+
+// VerifyWithKey verifies signature over digest using the named Key Vault key.
+func (a *AzureKeyVaultService) VerifyWithKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret, algorithm azkeys.SignatureAlgorithm, digest, signature []byte) (bool, error) {
+	keysClient, err := getKeysClient(secret)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := keysClient.Verify(context.Background(), secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version, azkeys.VerifyParameters{
+		Algorithm: &algorithm,
+		Digest:    digest,
+		Signature: signature,
+	}, nil)
+	if err != nil {
+		return false, fmt.Errorf("failed to verify signature with azure key vault key, error: %+v", err)
+	}
+
+	return result.Value != nil && *result.Value, nil
+}
+
+// EncryptWithKey encrypts plaintext using the named Key Vault key.
+func (a *AzureKeyVaultService) EncryptWithKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret, algorithm azkeys.EncryptionAlgorithm, plaintext []byte) ([]byte, error) {
+	keysClient, err := getKeysClient(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := keysClient.Encrypt(context.Background(), secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version, azkeys.KeyOperationParameters{
+		Algorithm: &algorithm,
+		Value:     plaintext,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encrypt with azure key vault key, error: %+v", err)
+	}
+
+	return result.Result, nil
+}
+
+// DecryptWithKey decrypts ciphertext using the named Key Vault key, without
+// the private key material ever leaving the vault.
+func (a *AzureKeyVaultService) DecryptWithKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret, algorithm azkeys.EncryptionAlgorithm, ciphertext []byte) ([]byte, error) {
+	keysClient, err := getKeysClient(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := keysClient.Decrypt(context.Background(), secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version, azkeys.KeyOperationParameters{
+		Algorithm: &algorithm,
+		Value:     ciphertext,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt with azure key vault key, error: %+v", err)
+	}
+
+	return result.Result, nil
+}
+
+// WrapKeyWithKey wraps keyMaterial using the named Key Vault key.
+func (a *AzureKeyVaultService) WrapKeyWithKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret, algorithm azkeys.EncryptionAlgorithm, keyMaterial []byte) ([]byte, error) {
+	keysClient, err := getKeysClient(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := keysClient.WrapKey(context.Background(), secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version, azkeys.KeyOperationParameters{
+		Algorithm: &algorithm,
+		Value:     keyMaterial,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap key with azure key vault key, error: %+v", err)
+	}
+
+	return result.Result, nil
+}
+
+// UnwrapKeyWithKey unwraps a wrapped key using the named Key Vault key,
+// without the unwrapping key material ever leaving the vault.
+func (a *AzureKeyVaultService) UnwrapKeyWithKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret, algorithm azkeys.EncryptionAlgorithm, wrappedKey []byte) ([]byte, error) {
+	keysClient, err := getKeysClient(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	result, err := keysClient.UnwrapKey(context.Background(), secret.Spec.Vault.Object.Name, secret.Spec.Vault.Object.Version, azkeys.KeyOperationParameters{
+		Algorithm: &algorithm,
+		Value:     wrappedKey,
+	}, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key with azure key vault key, error: %+v", err)
+	}
+
+	return result.Result, nil
+}
+
+// Recognized OutputSecretKey.SrcName values for splitting a PEM certificate
+// chain across several output keys, in addition to the default combined
+// tls.crt/tls.key. Selecting these requires ConstructPEMChain so the chain is
+// known to be ordered leaf-first.
+const (
+	chainSrcNameLeaf          = "leaf"
+	chainSrcNameIntermediates = "intermediates"
+	chainSrcNameRoot          = "root"
+)
+
+// extractPemCertificate walks every PEM block in pemCert and splits it into
+// the full server->intermediate->root chain (tls.crt) and the private key
+// (tls.key). When secret.Spec.Vault.Object.ConstructPEMChain is set, the
+// chain is reordered leaf-first, mirroring the CSI provider's
+// construct-pem-chain option, since Key Vault does not guarantee ordering.
+// When ConstructPEMChain is set, it additionally writes the leaf,
+// intermediate and root certificates to separate output keys for any
+// secret.Spec.OutputSecret.Keys entry whose SrcName is "leaf",
+// "intermediates" or "root", so callers that need them
+// apart from the combined chain - e.g. to hand the root to a truststore on
+// its own - don't have to re-split tls.crt themselves.
+func extractPemCertificate(pemCert string, secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) map[string][]byte {
+	secretValue := make(map[string][]byte, 2)
+
+	var certs []*pem.Block
+	var keyBlock *pem.Block
+
+	rest := []byte(pemCert)
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			certs = append(certs, block)
+		} else {
+			keyBlock = block
+		}
+	}
+
+	if secret.Spec.Vault.Object.ConstructPEMChain {
+		certs = sortCertificateChainLeafFirst(certs)
+	}
+
+	var chain bytes.Buffer
+	for _, cert := range certs {
+		chain.Write(pem.EncodeToMemory(cert))
+	}
+	secretValue[corev1.TLSCertKey] = chain.Bytes()
+
+	if keyBlock != nil {
+		secretValue[corev1.TLSPrivateKeyKey] = pem.EncodeToMemory(keyBlock)
+	}
+
+	if secret.Spec.Vault.Object.ConstructPEMChain {
+		for _, key := range secret.Spec.OutputSecret.Keys {
+			switch key.SrcName {
+			case chainSrcNameLeaf:
+				if len(certs) > 0 {
+					secretValue[key.DstName] = pem.EncodeToMemory(certs[0])
+				}
+			case chainSrcNameIntermediates:
+				if len(certs) > 2 {
+					var intermediates bytes.Buffer
+					for _, cert := range certs[1 : len(certs)-1] {
+						intermediates.Write(pem.EncodeToMemory(cert))
+					}
+					secretValue[key.DstName] = intermediates.Bytes()
+				}
+			case chainSrcNameRoot:
+				if len(certs) > 1 {
+					secretValue[key.DstName] = pem.EncodeToMemory(certs[len(certs)-1])
+				}
+			}
+		}
+	}
+
 	return secretValue
 }
 
-func extractPfxCertificate(pfx string) (map[string][]byte, error) {
-	pfxRaw := make([]byte, 0)
+// sortCertificateChainLeafFirst reorders certs so the leaf (end-entity)
+// certificate comes first, by following each certificate's issuer to the
+// certificate it was issued by. If the chain can't be parsed or linked, the
+// original order is returned unchanged.
+func sortCertificateChainLeafFirst(certs []*pem.Block) []*pem.Block {
+	if len(certs) < 2 {
+		return certs
+	}
+
+	parsed := make([]*x509.Certificate, len(certs))
+	issuerOf := make(map[string]int, len(certs))
+	for i, block := range certs {
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return certs
+		}
+		parsed[i] = cert
+		issuerOf[cert.Subject.String()] = i
+	}
+
+	isIssuer := make(map[int]bool, len(certs))
+	for _, cert := range parsed {
+		if idx, ok := issuerOf[cert.Issuer.String()]; ok && cert.Issuer.String() != cert.Subject.String() {
+			isIssuer[idx] = true
+		}
+	}
+
+	leaf := -1
+	for i := range parsed {
+		if !isIssuer[i] {
+			leaf = i
+			break
+		}
+	}
+	if leaf == -1 {
+		return certs
+	}
+
+	sorted := make([]*pem.Block, 0, len(certs))
+	for current, used := leaf, map[int]bool{}; !used[current]; {
+		sorted = append(sorted, certs[current])
+		used[current] = true
+		next, ok := issuerOf[parsed[current].Issuer.String()]
+		if !ok || used[next] {
+			break
+		}
+		current = next
+	}
+
+	if len(sorted) != len(certs) {
+		return certs
+	}
+	return sorted
+}
+
+func extractPfxCertificate(pfx string, secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
 	secretValue := make(map[string][]byte, 2)
 
-	_, err := base64.RawURLEncoding.Decode(pfxRaw, []byte(pfx))
+	pfxRaw, err := base64.StdEncoding.DecodeString(pfx)
 	if err != nil {
 		return secretValue, fmt.Errorf("failed to decode base64 encoded pfx certificate, error: %+v", err)
 	}
 
-	pemList, err := pkcs12.ToPEM(pfxRaw, "")
+	pemList, err := pkcs12.ToPEM(pfxRaw, secret.Spec.Vault.Object.Password)
 	if err != nil {
 		return secretValue, fmt.Errorf("failed to convert pfx certificate to pem, error: %+v", err)
 	}
@@ -142,80 +584,91 @@ func extractPfxCertificate(pfx string) (map[string][]byte, error) {
 		mergedPems.WriteString(string(pem.EncodeToMemory(pemCert)))
 	}
 
-	return extractPemCertificate(mergedPems.String()), nil
+	return extractPemCertificate(mergedPems.String(), secret), nil
+}
+
+// getCredential resolves the Azure credential used by all three Key Vault
+// clients. azidentity.NewDefaultAzureCredential tries Workload Identity
+// (AKS federated service account tokens, via AZURE_FEDERATED_TOKEN_FILE etc.)
+// before falling back through the rest of the default chain, so the
+// controller keeps working on clusters that have dropped AAD Pod Identity.
+func getCredential() (azcore.TokenCredential, error) {
+	return azidentity.NewDefaultAzureCredential(nil)
+}
+
+// keyVaultDNSSuffix resolves the Key Vault DNS suffix for cloud, so vaults in
+// Azure Government, China, Germany and other sovereign clouds can be reached
+// without hard-coding the public "vault.azure.net" suffix. An empty cloud
+// defaults to the Azure public cloud.
+func keyVaultDNSSuffix(cloud azureKeyVaultSecretv1alpha1.AzureKeyVaultCloud) (string, error) {
+	switch cloud {
+	case "", azureKeyVaultSecretv1alpha1.AzureKeyVaultCloudPublic:
+		return azure.PublicCloud.KeyVaultDNSSuffix, nil
+	case azureKeyVaultSecretv1alpha1.AzureKeyVaultCloudUSGovernment:
+		return azure.USGovernmentCloud.KeyVaultDNSSuffix, nil
+	case azureKeyVaultSecretv1alpha1.AzureKeyVaultCloudChina:
+		return azure.ChinaCloud.KeyVaultDNSSuffix, nil
+	case azureKeyVaultSecretv1alpha1.AzureKeyVaultCloudGerman:
+		return azure.GermanCloud.KeyVaultDNSSuffix, nil
+	default:
+		return "", fmt.Errorf("unsupported azure key vault cloud '%s'", cloud)
+	}
+}
+
+// vaultURL resolves the base URL for secret's vault. An explicit
+// spec.vault.baseUrl always wins; otherwise the URL is derived from
+// spec.vault.name and the DNS suffix for spec.vault.cloud.
+func vaultURL(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (string, error) {
+	if secret.Spec.Vault.BaseURL != "" {
+		return secret.Spec.Vault.BaseURL, nil
+	}
+
+	suffix, err := keyVaultDNSSuffix(secret.Spec.Vault.Cloud)
+	if err != nil {
+		return "", err
+	}
+
+	return fmt.Sprintf("https://%s.%s", secret.Spec.Vault.Name, suffix), nil
+}
+
+func getSecretsClient(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (*azsecrets.Client, error) {
+	baseURL, err := vaultURL(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := getCredential()
+	if err != nil {
+		return nil, err
+	}
+
+	return azsecrets.NewClient(baseURL, credential, nil)
+}
+
+func getCertificatesClient(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (*azcertificates.Client, error) {
+	baseURL, err := vaultURL(secret)
+	if err != nil {
+		return nil, err
+	}
+
+	credential, err := getCredential()
+	if err != nil {
+		return nil, err
+	}
+
+	return azcertificates.NewClient(baseURL, credential, nil)
 }
 
-func getClient(resource string) (*keyvault.BaseClient, error) {
-	authorizer, err := auth.NewAuthorizerFromEnvironmentWithResource(resource)
+func getKeysClient(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (*azkeys.Client, error) {
+	baseURL, err := vaultURL(secret)
 	if err != nil {
 		return nil, err
 	}
 
-	keyClient := keyvault.New()
-	keyClient.Authorizer = authorizer
-
-	return &keyClient, nil
-}
-
-// func base64EncodeString(value string) []byte {
-// 	return base64Encode([]byte(value))
-// }
-//
-// func base64Encode(src []byte) []byte {
-// 	sliceLen := base64.RawStdEncoding.EncodedLen(len(src))
-// 	log.Debugf("size of value to base64 encode is %d", sliceLen)
-// 	dst := make([]byte, sliceLen)
-// 	base64.RawStdEncoding.Encode(dst, src)
-// 	return dst
-// }
-
-// // GetCertificate returns a certificate from Azure Key Vault
-// func (a *AzureKeyVaultService) getCertificate(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (string, error) {
-// 	//Get secret value from Azure Key Vault
-// 	vaultClient, err := a.getClient("https://vault.azure.net")
-// 	if err != nil {
-// 		return "", err
-// 	}
-//
-// 	baseURL := fmt.Sprintf("https://%s.vault.azure.net", secret.Spec.Vault.Name)
-// 	certBundle, err := vaultClient.GetCertificate(context.Background(), baseURL, secret.Spec.Vault.ObjectName, "")
-//
-// 	if err != nil {
-// 		return "", err
-// 	}
-//
-// 	return string(*certBundle.Cer), nil
-// }
-
-// // GetSecret returns a secret from Azure Key Vault
-// func (a *AzureKeyVaultService) GetKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (string, error) {
-// 	//Get secret value from Azure Key Vault
-// 	vaultClient, err := a.getClient("https://vault.azure.net")
-// 	if err != nil {
-// 		return "", err
-// 	}
-//
-// 	baseURL := fmt.Sprintf("https://%s.vault.azure.net", secret.Spec.Vault.Name)
-// 	secretPack, err := vaultClient.GetKey(context.Background(), baseURL, secret.Spec.Vault.ObjectName, "")
-//
-// 	if err != nil {
-// 		return "", err
-// 	}
-// 	return *secretPack.Value, nil
-// }
-
-// func decodePem(certInput string) tls.Certificate {
-// 	var cert tls.Certificate
-// 	certPEMBlock := []byte(certInput)
-// 	var certDERBlock *pem.Block
-// 	for {
-// 		certDERBlock, certPEMBlock = pem.Decode(certPEMBlock)
-// 		if certDERBlock == nil {
-// 			break
-// 		}
-// 		if certDERBlock.Type == "CERTIFICATE" {
-// 			cert.Certificate = append(cert.Certificate, certDERBlock.Bytes)
-// 		}
-// 	}
-// 	return cert
-// }
+	credential, err := getCredential()
+	if err != nil {
+		return nil, err
+	}
+
+	return azkeys.NewClient(baseURL, credential, nil)
+}
@@ -0,0 +1,260 @@
+package vault
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/security/keyvault/azkeys"
+	corev1 "k8s.io/api/core/v1"
+
+	azureKeyVaultSecretv1alpha1 "github.com/SparebankenVest/azure-keyvault-controller/pkg/apis/azurekeyvaultcontroller/v1alpha1"
+)
+
+func keyType(t azkeys.KeyType) *azkeys.KeyType { return &t }
+func curveName(c azkeys.CurveName) *azkeys.CurveName { return &c }
+
+func TestPublicKeyFromJWKRSA(t *testing.T) {
+	jwk := &azkeys.JSONWebKey{
+		Kty: keyType(azkeys.KeyTypeRSA),
+		N:   []byte{0x01, 0x02, 0x03},
+		E:   []byte{0x01, 0x00, 0x01}, // 65537
+	}
+
+	pub, err := publicKeyFromJWK(jwk)
+	if err != nil {
+		t.Fatalf("publicKeyFromJWK() error = %v, want nil", err)
+	}
+
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		t.Fatalf("publicKeyFromJWK() returned %T, want *rsa.PublicKey", pub)
+	}
+	if rsaPub.E != 65537 {
+		t.Errorf("rsaPub.E = %d, want 65537", rsaPub.E)
+	}
+}
+
+func TestPublicKeyFromJWKRSAMissingModulus(t *testing.T) {
+	jwk := &azkeys.JSONWebKey{
+		Kty: keyType(azkeys.KeyTypeRSA),
+		E:   []byte{0x01, 0x00, 0x01},
+	}
+
+	if _, err := publicKeyFromJWK(jwk); err == nil {
+		t.Fatal("publicKeyFromJWK() error = nil, want error for missing modulus")
+	}
+}
+
+func TestPublicKeyFromJWKRSAExponentTooLong(t *testing.T) {
+	jwk := &azkeys.JSONWebKey{
+		Kty: keyType(azkeys.KeyTypeRSA),
+		N:   []byte{0x01, 0x02, 0x03},
+		E:   make([]byte, 9),
+	}
+
+	if _, err := publicKeyFromJWK(jwk); err == nil {
+		t.Fatal("publicKeyFromJWK() error = nil, want error for oversized exponent")
+	}
+}
+
+func TestPublicKeyFromJWKEC(t *testing.T) {
+	jwk := &azkeys.JSONWebKey{
+		Kty: keyType(azkeys.KeyTypeEC),
+		Crv: curveName(azkeys.CurveNameP256),
+		X:   []byte{0x01, 0x02, 0x03},
+		Y:   []byte{0x04, 0x05, 0x06},
+	}
+
+	pub, err := publicKeyFromJWK(jwk)
+	if err != nil {
+		t.Fatalf("publicKeyFromJWK() error = %v, want nil", err)
+	}
+	if _, ok := pub.(*ecdsa.PublicKey); !ok {
+		t.Fatalf("publicKeyFromJWK() returned %T, want *ecdsa.PublicKey", pub)
+	}
+}
+
+func TestPublicKeyFromJWKUnsupportedCurve(t *testing.T) {
+	jwk := &azkeys.JSONWebKey{
+		Kty: keyType(azkeys.KeyTypeEC),
+		Crv: curveName("P-999"),
+		X:   []byte{0x01},
+		Y:   []byte{0x02},
+	}
+
+	if _, err := publicKeyFromJWK(jwk); err == nil {
+		t.Fatal("publicKeyFromJWK() error = nil, want error for unsupported curve")
+	}
+}
+
+func TestPublicKeyFromJWKUnsupportedType(t *testing.T) {
+	jwk := &azkeys.JSONWebKey{
+		Kty: keyType("oct"),
+	}
+
+	if _, err := publicKeyFromJWK(jwk); err == nil {
+		t.Fatal("publicKeyFromJWK() error = nil, want error for unsupported key type")
+	}
+}
+
+// selfSignedChain returns a root CA certificate and a leaf certificate it
+// signed, each as a "CERTIFICATE" pem.Block.
+func selfSignedChain(t *testing.T) (leaf, root *pem.Block) {
+	t.Helper()
+
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate root key: %v", err)
+	}
+	rootTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-root"},
+		NotBefore:             time.Unix(0, 0),
+		NotAfter:              time.Unix(0, 0).Add(24 * time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+	}
+	rootDER, err := x509.CreateCertificate(rand.Reader, rootTemplate, rootTemplate, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("failed to create root certificate: %v", err)
+	}
+	rootCert, err := x509.ParseCertificate(rootDER)
+	if err != nil {
+		t.Fatalf("failed to parse root certificate: %v", err)
+	}
+
+	leafKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate leaf key: %v", err)
+	}
+	leafTemplate := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "test-leaf"},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(24 * time.Hour),
+	}
+	leafDER, err := x509.CreateCertificate(rand.Reader, leafTemplate, rootCert, &leafKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatalf("failed to create leaf certificate: %v", err)
+	}
+
+	return &pem.Block{Type: "CERTIFICATE", Bytes: leafDER}, &pem.Block{Type: "CERTIFICATE", Bytes: rootDER}
+}
+
+func TestSortCertificateChainLeafFirst(t *testing.T) {
+	leaf, root := selfSignedChain(t)
+
+	sorted := sortCertificateChainLeafFirst([]*pem.Block{root, leaf})
+	if len(sorted) != 2 || string(sorted[0].Bytes) != string(leaf.Bytes) {
+		t.Fatalf("sortCertificateChainLeafFirst() did not put the leaf first")
+	}
+}
+
+func TestSortCertificateChainLeafFirstUnparsable(t *testing.T) {
+	garbage := []*pem.Block{{Type: "CERTIFICATE", Bytes: []byte("not a certificate")}, {Type: "CERTIFICATE", Bytes: []byte("also not one")}}
+
+	sorted := sortCertificateChainLeafFirst(garbage)
+	if len(sorted) != len(garbage) || sorted[0] != garbage[0] {
+		t.Fatalf("sortCertificateChainLeafFirst() should return the original order unchanged when certs can't be parsed")
+	}
+}
+
+func TestExtractPemCertificateSeparatesChainKeys(t *testing.T) {
+	leaf, root := selfSignedChain(t)
+	pemCert := string(pem.EncodeToMemory(root)) + string(pem.EncodeToMemory(leaf))
+
+	secret := &azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret{}
+	secret.Spec.Vault.Object.ConstructPEMChain = true
+	secret.Spec.OutputSecret.Keys = []azureKeyVaultSecretv1alpha1.OutputSecretKey{
+		{SrcName: chainSrcNameLeaf, DstName: "leaf.pem"},
+		{SrcName: chainSrcNameRoot, DstName: "root.pem"},
+	}
+
+	result := extractPemCertificate(pemCert, secret)
+
+	if string(result["leaf.pem"]) != string(pem.EncodeToMemory(leaf)) {
+		t.Errorf("result[leaf.pem] did not match the leaf certificate")
+	}
+	if string(result["root.pem"]) != string(pem.EncodeToMemory(root)) {
+		t.Errorf("result[root.pem] did not match the root certificate")
+	}
+	if len(result[corev1.TLSCertKey]) == 0 {
+		t.Errorf("result[%s] should still hold the combined chain", corev1.TLSCertKey)
+	}
+}
+
+func TestExtractPfxCertificateInvalidBase64(t *testing.T) {
+	secret := &azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret{}
+
+	if _, err := extractPfxCertificate("not-base64!!", secret); err == nil {
+		t.Fatal("extractPfxCertificate() error = nil, want error for invalid base64")
+	}
+}
+
+func TestSplitSecretValueRawCopiesVerbatim(t *testing.T) {
+	secret := &azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret{}
+	secret.Spec.OutputSecret.Keys = []azureKeyVaultSecretv1alpha1.OutputSecretKey{
+		{DstName: "a"},
+		{DstName: "b"},
+	}
+
+	result, err := splitSecretValue("hunter2", secret)
+	if err != nil {
+		t.Fatalf("splitSecretValue() error = %v, want nil", err)
+	}
+	if string(result["a"]) != "hunter2" || string(result["b"]) != "hunter2" {
+		t.Errorf("splitSecretValue() = %v, want every key set to the raw value", result)
+	}
+}
+
+func TestSplitSecretValueJSON(t *testing.T) {
+	secret := &azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret{}
+	secret.Spec.Vault.Object.SourceFormat = "json"
+	secret.Spec.OutputSecret.Keys = []azureKeyVaultSecretv1alpha1.OutputSecretKey{
+		{SrcName: "user", DstName: "username"},
+	}
+
+	result, err := splitSecretValue(`{"user":"alice","pass":"hunter2"}`, secret)
+	if err != nil {
+		t.Fatalf("splitSecretValue() error = %v, want nil", err)
+	}
+	if string(result["username"]) != "alice" {
+		t.Errorf("result[username] = %q, want \"alice\"", result["username"])
+	}
+}
+
+func TestSplitSecretValueMissingField(t *testing.T) {
+	secret := &azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret{}
+	secret.Spec.Vault.Object.SourceFormat = "json"
+	secret.Spec.OutputSecret.Keys = []azureKeyVaultSecretv1alpha1.OutputSecretKey{
+		{SrcName: "missing", DstName: "out"},
+	}
+
+	if _, err := splitSecretValue(`{"user":"alice"}`, secret); err == nil {
+		t.Fatal("splitSecretValue() error = nil, want error for a missing source field")
+	}
+}
+
+func TestParseSourceFieldsDotenv(t *testing.T) {
+	fields, err := parseSourceFields("USER=alice\nPASS=hunter2\n", AzureKeyVaultSecretFormatDotenv)
+	if err != nil {
+		t.Fatalf("parseSourceFields() error = %v, want nil", err)
+	}
+	if fields["USER"] != "alice" {
+		t.Errorf("fields[USER] = %q, want \"alice\"", fields["USER"])
+	}
+}
+
+func TestParseSourceFieldsUnsupportedFormat(t *testing.T) {
+	if _, err := parseSourceFields("anything", "xml"); err == nil {
+		t.Fatal("parseSourceFields() error = nil, want error for an unsupported format")
+	}
+}
@@ -0,0 +1,218 @@
+package vault
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"golang.org/x/sync/singleflight"
+
+	azureKeyVaultSecretv1alpha1 "github.com/SparebankenVest/azure-keyvault-controller/pkg/apis/azurekeyvaultcontroller/v1alpha1"
+)
+
+// defaultCacheTTL is used when CachingAzureKeyVaultService is constructed
+// with a ttl of 0.
+const defaultCacheTTL = 5 * time.Minute
+
+const maxRetries = 5
+
+var (
+	cacheHitsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "azure_key_vault_controller",
+		Subsystem: "cache",
+		Name:      "hits_total",
+		Help:      "Number of Azure Key Vault lookups served from the in-memory cache.",
+	})
+	cacheMissesTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "azure_key_vault_controller",
+		Subsystem: "cache",
+		Name:      "misses_total",
+		Help:      "Number of Azure Key Vault lookups that were not found in the in-memory cache.",
+	})
+	cacheEvictionsTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Namespace: "azure_key_vault_controller",
+		Subsystem: "cache",
+		Name:      "evictions_total",
+		Help:      "Number of cache entries evicted for having passed their TTL.",
+	})
+)
+
+type cacheKey struct {
+	vaultName  string
+	objectType AzureKeyVaultObjectType
+	name       string
+	version    string
+}
+
+type cacheEntry struct {
+	value     map[string][]byte
+	expiresAt time.Time
+}
+
+// CachingAzureKeyVaultService wraps a SecretStore with an in-memory TTL
+// cache keyed by (vault, objectType, name, version), plus singleflight
+// deduplication of concurrent fetches for the same key and an
+// exponential-backoff retry path for Key Vault throttling (HTTP 429). This
+// absorbs Azure Key Vault's per-vault throttling (2000 requests/10s
+// subscription-wide), which is easily tripped when many AzureKeyVaultSecret
+// CRs share a vault.
+type CachingAzureKeyVaultService struct {
+	inner SecretStore
+	ttl   time.Duration
+
+	mu    sync.Mutex
+	cache map[cacheKey]cacheEntry
+
+	group singleflight.Group
+}
+
+var _ SecretStore = &CachingAzureKeyVaultService{}
+
+// NewCachingAzureKeyVaultService wraps inner with a TTL cache. A ttl of 0
+// uses defaultCacheTTL.
+func NewCachingAzureKeyVaultService(inner SecretStore, ttl time.Duration) *CachingAzureKeyVaultService {
+	if ttl <= 0 {
+		ttl = defaultCacheTTL
+	}
+
+	return &CachingAzureKeyVaultService{
+		inner: inner,
+		ttl:   ttl,
+		cache: make(map[cacheKey]cacheEntry),
+	}
+}
+
+// NewDefaultSecretStore returns the SecretStore the controller should use to
+// resolve AzureKeyVaultSecret objects: an AzureKeyVaultService wrapped in
+// CachingAzureKeyVaultService with ttl, so reconciling many CRs against the
+// same vault object doesn't retrigger a live Key Vault call - and therefore
+// Key Vault's subscription-wide throttling - on every reconcile.
+func NewDefaultSecretStore(ttl time.Duration) SecretStore {
+	return NewCachingAzureKeyVaultService(NewAzureKeyVaultService(), ttl)
+}
+
+// GetSecret returns the cached secret value for secret, see cached.
+func (c *CachingAzureKeyVaultService) GetSecret(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+	return c.cached(secret, c.inner.GetSecret)
+}
+
+// GetCertificate returns the cached certificate value for secret, see cached.
+func (c *CachingAzureKeyVaultService) GetCertificate(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+	return c.cached(secret, c.inner.GetCertificate)
+}
+
+// GetKey returns the cached key value for secret, see cached.
+func (c *CachingAzureKeyVaultService) GetKey(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+	return c.cached(secret, c.inner.GetKey)
+}
+
+// cached returns the cached value for secret if present and unexpired,
+// otherwise it calls fetch - deduplicating concurrent fetches for the same
+// key and retrying on throttling - and caches the result for ttl. The cache
+// key is derived from secret.Spec.Vault.Object.Type rather than which
+// wrapper method was called, since GetSecret itself dispatches to
+// certificate/key handling based on that field - two CRs sharing a vault and
+// object name but with different Type values are different Key Vault
+// objects and must not collide on the same cache entry.
+func (c *CachingAzureKeyVaultService) cached(secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret, fetch func(*azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error)) (map[string][]byte, error) {
+	key := cacheKey{
+		vaultName:  secret.Spec.Vault.Name,
+		objectType: AzureKeyVaultObjectType(secret.Spec.Vault.Object.Type),
+		name:       secret.Spec.Vault.Object.Name,
+		version:    secret.Spec.Vault.Object.Version,
+	}
+
+	if value, ok := c.get(key); ok {
+		cacheHitsTotal.Inc()
+		return value, nil
+	}
+	cacheMissesTotal.Inc()
+
+	result, err, _ := c.group.Do(fmt.Sprintf("%+v", key), func() (interface{}, error) {
+		return fetchWithRetry(fetch, secret)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	value := result.(map[string][]byte)
+	c.set(key, value)
+	return value, nil
+}
+
+func (c *CachingAzureKeyVaultService) get(key cacheKey) (map[string][]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.cache[key]
+	if !ok {
+		return nil, false
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(c.cache, key)
+		cacheEvictionsTotal.Inc()
+		return nil, false
+	}
+
+	return entry.value, true
+}
+
+func (c *CachingAzureKeyVaultService) set(key cacheKey, value map[string][]byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.cache[key] = cacheEntry{value: value, expiresAt: time.Now().Add(c.ttl)}
+}
+
+// fetchWithRetry calls fetch, retrying with exponential backoff when Key
+// Vault responds with 429, honoring any Retry-After header the SDK surfaces.
+func fetchWithRetry(fetch func(*azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error), secret *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+	backoff := 500 * time.Millisecond
+
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		value, err := fetch(secret)
+		if err == nil {
+			return value, nil
+		}
+		lastErr = err
+
+		retryAfter, throttled := throttleRetryAfter(err)
+		if !throttled {
+			return nil, err
+		}
+		if retryAfter > 0 {
+			backoff = retryAfter
+		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+
+	return nil, fmt.Errorf("azure key vault request still throttled after %d attempts, last error: %+v", maxRetries, lastErr)
+}
+
+// throttleRetryAfter reports whether err is a Key Vault 429 response, and
+// the Retry-After duration it carries, if any.
+func throttleRetryAfter(err error) (time.Duration, bool) {
+	var respErr *azcore.ResponseError
+	if !errors.As(err, &respErr) || respErr.StatusCode != http.StatusTooManyRequests {
+		return 0, false
+	}
+	if respErr.RawResponse == nil {
+		return 0, true
+	}
+
+	if retryAfter := respErr.RawResponse.Header.Get("Retry-After"); retryAfter != "" {
+		if seconds, err := strconv.Atoi(retryAfter); err == nil {
+			return time.Duration(seconds) * time.Second, true
+		}
+	}
+
+	return 0, true
+}
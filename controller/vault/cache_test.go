@@ -0,0 +1,202 @@
+package vault
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Azure/azure-sdk-for-go/sdk/azcore"
+
+	azureKeyVaultSecretv1alpha1 "github.com/SparebankenVest/azure-keyvault-controller/pkg/apis/azurekeyvaultcontroller/v1alpha1"
+)
+
+// fakeStore is a SecretStore whose every method defers to fetch, counting
+// calls so tests can assert on caching/dedup/retry behavior without a live
+// Key Vault.
+type fakeStore struct {
+	mu    sync.Mutex
+	calls int
+	fetch func(callNum int) (map[string][]byte, error)
+}
+
+func (f *fakeStore) call() (map[string][]byte, error) {
+	f.mu.Lock()
+	f.calls++
+	callNum := f.calls
+	f.mu.Unlock()
+	return f.fetch(callNum)
+}
+
+func (f *fakeStore) GetSecret(*azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+	return f.call()
+}
+func (f *fakeStore) GetCertificate(*azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+	return f.call()
+}
+func (f *fakeStore) GetKey(*azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+	return f.call()
+}
+
+func testSecret() *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret {
+	secret := &azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret{}
+	secret.Spec.Vault.Name = "test-vault"
+	secret.Spec.Vault.Object.Name = "test-object"
+	return secret
+}
+
+func TestCachingAzureKeyVaultServiceCachesResult(t *testing.T) {
+	inner := &fakeStore{fetch: func(callNum int) (map[string][]byte, error) {
+		return map[string][]byte{"n": []byte(fmt.Sprintf("%d", callNum))}, nil
+	}}
+	cache := NewCachingAzureKeyVaultService(inner, time.Minute)
+	secret := testSecret()
+
+	first, err := cache.GetSecret(secret)
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v, want nil", err)
+	}
+	second, err := cache.GetSecret(secret)
+	if err != nil {
+		t.Fatalf("GetSecret() error = %v, want nil", err)
+	}
+
+	if string(first["n"]) != string(second["n"]) {
+		t.Errorf("second GetSecret() returned %q, want the cached %q", second["n"], first["n"])
+	}
+	if inner.calls != 1 {
+		t.Errorf("inner store was called %d times, want 1 (second call should hit the cache)", inner.calls)
+	}
+}
+
+func TestCachingAzureKeyVaultServiceExpiresAfterTTL(t *testing.T) {
+	inner := &fakeStore{fetch: func(callNum int) (map[string][]byte, error) {
+		return map[string][]byte{"n": []byte(fmt.Sprintf("%d", callNum))}, nil
+	}}
+	cache := NewCachingAzureKeyVaultService(inner, time.Millisecond)
+	secret := testSecret()
+
+	if _, err := cache.GetSecret(secret); err != nil {
+		t.Fatalf("GetSecret() error = %v, want nil", err)
+	}
+	time.Sleep(5 * time.Millisecond)
+	if _, err := cache.GetSecret(secret); err != nil {
+		t.Fatalf("GetSecret() error = %v, want nil", err)
+	}
+
+	if inner.calls != 2 {
+		t.Errorf("inner store was called %d times, want 2 (entry should have expired)", inner.calls)
+	}
+}
+
+func TestCachingAzureKeyVaultServiceDedupesConcurrentFetches(t *testing.T) {
+	const goroutines = 10
+
+	release := make(chan struct{})
+	inner := &fakeStore{fetch: func(callNum int) (map[string][]byte, error) {
+		<-release
+		return map[string][]byte{"n": []byte("v")}, nil
+	}}
+	cache := NewCachingAzureKeyVaultService(inner, time.Minute)
+	secret := testSecret()
+
+	// started is released only once every goroutine has begun calling
+	// GetSecret, so they're all racing to join the same singleflight call
+	// before fetch (and thus the call) is allowed to return - otherwise an
+	// earlier goroutine can complete and clear the in-flight call before a
+	// later one joins, producing a second, undeduped fetch.
+	var started sync.WaitGroup
+	started.Add(goroutines)
+
+	var wg sync.WaitGroup
+	for i := 0; i < goroutines; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			started.Done()
+			if _, err := cache.GetSecret(secret); err != nil {
+				t.Errorf("GetSecret() error = %v, want nil", err)
+			}
+		}()
+	}
+
+	started.Wait()
+	close(release)
+	wg.Wait()
+
+	if inner.calls != 1 {
+		t.Errorf("inner store was called %d times, want 1 (concurrent fetches for the same key should be deduped)", inner.calls)
+	}
+}
+
+func throttledResponseError(retryAfterSeconds string) *azcore.ResponseError {
+	header := http.Header{}
+	if retryAfterSeconds != "" {
+		header.Set("Retry-After", retryAfterSeconds)
+	}
+	return &azcore.ResponseError{
+		StatusCode:  http.StatusTooManyRequests,
+		RawResponse: &http.Response{Header: header},
+	}
+}
+
+func TestFetchWithRetryRetriesThrottledRequests(t *testing.T) {
+	secret := testSecret()
+	fetch := func(s *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+		return nil, throttledResponseError("0")
+	}
+
+	calls := 0
+	wrapped := func(s *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+		calls++
+		if calls < 2 {
+			return fetch(s)
+		}
+		return map[string][]byte{"n": []byte("v")}, nil
+	}
+
+	value, err := fetchWithRetry(wrapped, secret)
+	if err != nil {
+		t.Fatalf("fetchWithRetry() error = %v, want nil", err)
+	}
+	if string(value["n"]) != "v" {
+		t.Errorf("fetchWithRetry() = %v, want the eventual success value", value)
+	}
+	if calls != 2 {
+		t.Errorf("fetch was called %d times, want 2 (one throttled, one success)", calls)
+	}
+}
+
+func TestFetchWithRetryDoesNotRetryNonThrottledErrors(t *testing.T) {
+	secret := testSecret()
+	calls := 0
+	wantErr := fmt.Errorf("boom")
+	fetch := func(s *azureKeyVaultSecretv1alpha1.AzureKeyVaultSecret) (map[string][]byte, error) {
+		calls++
+		return nil, wantErr
+	}
+
+	if _, err := fetchWithRetry(fetch, secret); err != wantErr {
+		t.Errorf("fetchWithRetry() error = %v, want %v", err, wantErr)
+	}
+	if calls != 1 {
+		t.Errorf("fetch was called %d times, want 1 (non-throttled errors should not be retried)", calls)
+	}
+}
+
+func TestThrottleRetryAfterParsesHeader(t *testing.T) {
+	retryAfter, throttled := throttleRetryAfter(throttledResponseError("7"))
+	if !throttled {
+		t.Fatal("throttleRetryAfter() throttled = false, want true for a 429 ResponseError")
+	}
+	if retryAfter != 7*time.Second {
+		t.Errorf("throttleRetryAfter() = %v, want 7s", retryAfter)
+	}
+}
+
+func TestThrottleRetryAfterIgnoresOtherErrors(t *testing.T) {
+	if _, throttled := throttleRetryAfter(fmt.Errorf("boom")); throttled {
+		t.Error("throttleRetryAfter() throttled = true, want false for a non-ResponseError")
+	}
+}
@@ -0,0 +1,5 @@
+// Package v1alpha1 contains the v1alpha1 API group for the
+// azurekeyvaultcontroller custom resources, starting with AzureKeyVaultSecret.
+// +k8s:deepcopy-gen=package
+// +groupName=azurekeyvaultcontroller.sparebankenvest.no
+package v1alpha1
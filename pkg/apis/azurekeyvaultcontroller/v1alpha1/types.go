@@ -0,0 +1,100 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// +genclient
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AzureKeyVaultSecret describes an Azure Key Vault secret, certificate or key
+// to sync into a Kubernetes Secret.
+type AzureKeyVaultSecret struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   AzureKeyVaultSecretSpec   `json:"spec"`
+	Status AzureKeyVaultSecretStatus `json:"status,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+
+// AzureKeyVaultSecretList is a list of AzureKeyVaultSecret resources.
+type AzureKeyVaultSecretList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []AzureKeyVaultSecret `json:"items"`
+}
+
+// AzureKeyVaultSecretSpec is the desired state of an AzureKeyVaultSecret.
+type AzureKeyVaultSecretSpec struct {
+	Vault        AzureKeyVault `json:"vault"`
+	OutputSecret OutputSecret  `json:"output"`
+}
+
+// AzureKeyVault identifies the vault and object to fetch from it.
+type AzureKeyVault struct {
+	// Name is the Key Vault name, used to derive the vault's base URL unless
+	// BaseURL is set explicitly.
+	Name   string              `json:"name"`
+	Object AzureKeyVaultObject `json:"object"`
+	// BaseURL overrides the vault URL derived from Name and Cloud, for
+	// private endpoints or non-standard DNS suffixes.
+	BaseURL string `json:"baseUrl,omitempty"`
+	// Cloud selects which sovereign cloud's Key Vault DNS suffix to use when
+	// BaseURL is not set. Defaults to the Azure public cloud.
+	Cloud AzureKeyVaultCloud `json:"cloud,omitempty"`
+}
+
+// AzureKeyVaultCloud selects an Azure sovereign cloud.
+type AzureKeyVaultCloud string
+
+const (
+	// AzureKeyVaultCloudPublic is the default Azure public cloud.
+	AzureKeyVaultCloudPublic AzureKeyVaultCloud = "AzurePublicCloud"
+	// AzureKeyVaultCloudUSGovernment is the Azure US Government cloud.
+	AzureKeyVaultCloudUSGovernment AzureKeyVaultCloud = "AzureUSGovernmentCloud"
+	// AzureKeyVaultCloudChina is the Azure China cloud.
+	AzureKeyVaultCloudChina AzureKeyVaultCloud = "AzureChinaCloud"
+	// AzureKeyVaultCloudGerman is the Azure Germany cloud.
+	AzureKeyVaultCloudGerman AzureKeyVaultCloud = "AzureGermanCloud"
+)
+
+// AzureKeyVaultObject identifies a single object within a vault.
+type AzureKeyVaultObject struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Version string `json:"version,omitempty"`
+	// Password decrypts a PKCS#12 (PFX) certificate object. Ignored for PEM
+	// certificates and other object types.
+	Password string `json:"password,omitempty"`
+	// ConstructPEMChain reorders a PEM certificate chain leaf-first, since
+	// Key Vault does not guarantee ordering.
+	ConstructPEMChain bool `json:"constructPEMChain,omitempty"`
+	// SourceFormat tells the controller how to parse a secret's value before
+	// mapping it onto OutputSecret.Keys. Defaults to "raw" (copy verbatim).
+	SourceFormat string `json:"sourceFormat,omitempty"`
+}
+
+// OutputSecret describes the Kubernetes Secret keys to populate from the
+// vault object.
+type OutputSecret struct {
+	Keys []OutputSecretKey `json:"keys,omitempty"`
+}
+
+// OutputSecretKey maps a field from the vault object's value onto a key in
+// the output Kubernetes Secret.
+type OutputSecretKey struct {
+	// SrcName names the field to read from the vault object's value once
+	// parsed according to AzureKeyVaultObject.SourceFormat. Ignored for the
+	// default "raw" format, where the whole value is copied into every key.
+	// For certificate objects with ConstructPEMChain set, a SrcName of
+	// "leaf", "intermediates" or "root" selects that part of the chain.
+	SrcName string `json:"srcName,omitempty"`
+	DstName string `json:"dstName"`
+}
+
+// AzureKeyVaultSecretStatus is the observed state of an AzureKeyVaultSecret.
+type AzureKeyVaultSecretStatus struct {
+}